@@ -0,0 +1,156 @@
+package sqlast
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+// Formatter is implemented by AST nodes that know how to render themselves
+// for a specific Dialect. Types in this package that do not yet implement
+// Formatter fall back to their existing Eval() string method, so Format and
+// Eval can be adopted node-by-node as the rest of the tree migrates.
+type Formatter interface {
+	Format(out io.Writer, dialect Dialect) error
+}
+
+// Dialect parameterizes how a Formatter renders dialect-specific SQL
+// syntax: identifier quoting, keyword casing, and constructs (LIMIT/OFFSET
+// vs TOP) that differ between database engines.
+type Dialect interface {
+	// QuoteIdent quotes a raw identifier according to this dialect's rules,
+	// e.g. `"ident"` for Postgres/ANSI, `` `ident` `` for MySQL, `[ident]`
+	// for MSSQL.
+	QuoteIdent(ident string) string
+	// Keyword cases a reserved word (or phrase, e.g. "GROUP BY") according
+	// to this dialect's casing preference.
+	Keyword(kw string) string
+	// UsesTopClause reports whether this dialect expresses row limiting as
+	// a TOP clause immediately after SELECT (MSSQL) rather than as a
+	// trailing LIMIT clause.
+	UsesTopClause() bool
+	// FormatLimit renders the fragment for limit: for a trailing-LIMIT
+	// dialect this is appended after the query body ("LIMIT 10"); for a
+	// TOP dialect this is injected right after the SELECT keyword, or after
+	// DISTINCT when present ("TOP 10").
+	FormatLimit(limit ASTNode) (string, error)
+	// SupportsApply reports whether this dialect accepts the T-SQL
+	// CROSS APPLY / OUTER APPLY join operators. It is false for
+	// ANSI/Postgres/MySQL, which have no APPLY equivalent.
+	SupportsApply() bool
+}
+
+type baseDialect struct {
+	openQuote  string
+	closeQuote string
+}
+
+func (b baseDialect) QuoteIdent(ident string) string {
+	return b.openQuote + ident + b.closeQuote
+}
+
+func (baseDialect) Keyword(kw string) string {
+	return kw
+}
+
+func (baseDialect) SupportsApply() bool { return false }
+
+type ansiDialect struct{ baseDialect }
+
+func (ansiDialect) UsesTopClause() bool { return false }
+
+func (ansiDialect) FormatLimit(limit ASTNode) (string, error) {
+	s, err := formatEval(AnsiDialect, limit)
+	if err != nil {
+		return "", err
+	}
+	return "LIMIT " + s, nil
+}
+
+type postgresDialect struct{ baseDialect }
+
+func (postgresDialect) UsesTopClause() bool { return false }
+
+func (postgresDialect) FormatLimit(limit ASTNode) (string, error) {
+	s, err := formatEval(PostgresDialect, limit)
+	if err != nil {
+		return "", err
+	}
+	return "LIMIT " + s, nil
+}
+
+type mysqlDialect struct{ baseDialect }
+
+func (mysqlDialect) UsesTopClause() bool { return false }
+
+func (mysqlDialect) FormatLimit(limit ASTNode) (string, error) {
+	s, err := formatEval(MySQLDialect, limit)
+	if err != nil {
+		return "", err
+	}
+	return "LIMIT " + s, nil
+}
+
+type mssqlDialect struct{ baseDialect }
+
+func (mssqlDialect) UsesTopClause() bool { return true }
+
+func (mssqlDialect) FormatLimit(limit ASTNode) (string, error) {
+	s, err := formatEval(MSSQLDialect, limit)
+	if err != nil {
+		return "", err
+	}
+	return "TOP " + s, nil
+}
+
+func (mssqlDialect) SupportsApply() bool { return true }
+
+var (
+	// AnsiDialect is the default dialect used by Eval(). It double-quotes
+	// identifiers and renders a trailing LIMIT clause.
+	AnsiDialect Dialect = ansiDialect{baseDialect{openQuote: `"`, closeQuote: `"`}}
+	// PostgresDialect double-quotes identifiers, as ANSI does.
+	PostgresDialect Dialect = postgresDialect{baseDialect{openQuote: `"`, closeQuote: `"`}}
+	// MySQLDialect quotes identifiers with backticks.
+	MySQLDialect Dialect = mysqlDialect{baseDialect{openQuote: "`", closeQuote: "`"}}
+	// MSSQLDialect brackets identifiers and renders limits as a leading
+	// TOP clause instead of a trailing LIMIT.
+	MSSQLDialect Dialect = mssqlDialect{baseDialect{openQuote: "[", closeQuote: "]"}}
+)
+
+// quoteIdent renders ident quoted according to dialect, for the plain
+// (non-qualified) identifiers that appear as aliases and USING columns.
+func quoteIdent(dialect Dialect, ident *SQLIdent) string {
+	return dialect.QuoteIdent(string(*ident))
+}
+
+// formatEval renders node for dialect: if node implements Formatter, its
+// Format method is used, otherwise it falls back to node.Eval(), which lets
+// nodes outside this package (or not yet migrated) participate unchanged.
+func formatEval(dialect Dialect, node ASTNode) (string, error) {
+	if node == nil {
+		return "", nil
+	}
+	if f, ok := node.(Formatter); ok {
+		var buf bytes.Buffer
+		if err := f.Format(&buf, dialect); err != nil {
+			return "", err
+		}
+		return buf.String(), nil
+	}
+	return node.Eval(), nil
+}
+
+// formatCommaSeparated renders nodes with formatEval and joins them with
+// ", ", mirroring commaSeparatedString for dialect-aware callers.
+func formatCommaSeparated(dialect Dialect, nodes []ASTNode) (string, error) {
+	parts := make([]string, 0, len(nodes))
+	for _, n := range nodes {
+		s, err := formatEval(dialect, n)
+		if err != nil {
+			return "", err
+		}
+		parts = append(parts, s)
+	}
+	return strings.Join(parts, ", "), nil
+}
@@ -0,0 +1,323 @@
+package sqlast
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDialect_QuoteIdent(t *testing.T) {
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, `"col"`},
+		{"postgres", PostgresDialect, `"col"`},
+		{"mysql", MySQLDialect, "`col`"},
+		{"mssql", MSSQLDialect, "[col]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if act := c.dialect.QuoteIdent("col"); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestSQLQuery_Format_TrailingLimit(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("test_table"),
+				},
+			},
+		},
+		Limit: NewLongValue(10),
+	}
+
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, "SELECT * FROM test_table LIMIT 10"},
+		{"postgres", PostgresDialect, "SELECT * FROM test_table LIMIT 10"},
+		{"mysql", MySQLDialect, "SELECT * FROM test_table LIMIT 10"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := query.Format(&buf, c.dialect); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act := buf.String(); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestTable_Format_QuotesAliasPerDialect(t *testing.T) {
+	table := &Table{
+		Name:  NewSQLObjectName("test_table"),
+		Alias: NewSQLIdent("t1"),
+	}
+
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, `test_table AS "t1"`},
+		{"mysql", MySQLDialect, "test_table AS `t1`"},
+		{"mssql", MSSQLDialect, "test_table AS [t1]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := table.Format(&buf, c.dialect); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act := buf.String(); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestDerived_Format_QuotesAliasPerDialect(t *testing.T) {
+	derived := &Derived{
+		SubQuery: &SQLQuery{
+			Body: &SelectExpr{
+				Select: &SQLSelect{
+					Projection: []SQLSelectItem{
+						&UnnamedExpression{Node: &SQLWildcard{}},
+					},
+					Relation: &Table{Name: NewSQLObjectName("t2")},
+				},
+			},
+		},
+		Alias: NewSQLIdent("sub"),
+	}
+
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, `(SELECT * FROM t2) AS "sub"`},
+		{"mysql", MySQLDialect, "(SELECT * FROM t2) AS `sub`"},
+		{"mssql", MSSQLDialect, "(SELECT * FROM t2) AS [sub]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := derived.Format(&buf, c.dialect); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act := buf.String(); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestUsingConstant_Format_QuotesColumnsPerDialect(t *testing.T) {
+	using := &UsingConstant{Idents: []*SQLIdent{NewSQLIdent("id")}}
+
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, ` USING("id")`},
+		{"mysql", MySQLDialect, " USING(`id`)"},
+		{"mssql", MSSQLDialect, " USING([id])"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			act, err := using.Suffix(c.dialect)
+			if err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestSQLQuery_Format_QuotesCTEAliasPerDialect(t *testing.T) {
+	query := &SQLQuery{
+		CTEs: []*CTE{
+			{
+				Alias: NewSQLIdent("cte"),
+				Query: &SQLQuery{
+					Body: &SelectExpr{
+						Select: &SQLSelect{
+							Projection: []SQLSelectItem{
+								&UnnamedExpression{Node: &SQLWildcard{}},
+							},
+							Relation: &Table{Name: NewSQLObjectName("t")},
+						},
+					},
+				},
+			},
+		},
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{Name: NewSQLObjectName("cte")},
+			},
+		},
+	}
+
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, `WITH "cte" AS (SELECT * FROM t) SELECT * FROM cte`},
+		{"mysql", MySQLDialect, "WITH `cte` AS (SELECT * FROM t) SELECT * FROM cte"},
+		{"mssql", MSSQLDialect, "WITH [cte] AS (SELECT * FROM t) SELECT * FROM cte"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := query.Format(&buf, c.dialect); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act := buf.String(); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestJoin_Format_ApplyGatedByDialect(t *testing.T) {
+	joins := map[JoinOperator]string{
+		CrossApply: "CROSS APPLY t2",
+		OuterApply: "OUTER APPLY t2",
+	}
+
+	for op, want := range joins {
+		join := &Join{
+			Relation: &Table{Name: NewSQLObjectName("t2")},
+			Op:       op,
+		}
+
+		t.Run("mssql accepts", func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := join.Format(&buf, MSSQLDialect); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act := buf.String(); act != " "+want {
+				t.Errorf("must be %s but %s", " "+want, act)
+			}
+		})
+
+		for name, dialect := range map[string]Dialect{"ansi": AnsiDialect, "postgres": PostgresDialect, "mysql": MySQLDialect} {
+			t.Run(name+" rejects", func(t *testing.T) {
+				var buf bytes.Buffer
+				if err := join.Format(&buf, dialect); err == nil {
+					t.Errorf("expected an error rejecting APPLY, got none")
+				}
+			})
+		}
+	}
+}
+
+func TestSQLQuery_Format_MSSQLTop(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("test_table"),
+				},
+			},
+		},
+		Limit: NewLongValue(10),
+	}
+
+	var buf bytes.Buffer
+	if err := query.Format(&buf, MSSQLDialect); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "SELECT TOP 10 * FROM test_table"
+	if act := buf.String(); act != expected {
+		t.Errorf("must be %s but %s", expected, act)
+	}
+}
+
+func TestSQLQuery_Format_MSSQLTopWithDistinct(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Distinct: true,
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("test_table"),
+				},
+			},
+		},
+		Limit: NewLongValue(10),
+	}
+
+	var buf bytes.Buffer
+	if err := query.Format(&buf, MSSQLDialect); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := "SELECT DISTINCT TOP 10 * FROM test_table"
+	if act := buf.String(); act != expected {
+		t.Errorf("must be %s but %s", expected, act)
+	}
+}
+
+func TestExpressionWithAlias_Format_QuotesAliasPerDialect(t *testing.T) {
+	expr := &ExpressionWithAlias{
+		Expr:  NewSQLIdentifier(NewSQLIdent("amount")),
+		Alias: NewSQLIdent("total"),
+	}
+
+	cases := []struct {
+		name    string
+		dialect Dialect
+		out     string
+	}{
+		{"ansi", AnsiDialect, `amount AS "total"`},
+		{"mysql", MySQLDialect, "amount AS `total`"},
+		{"mssql", MSSQLDialect, "amount AS [total]"},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := expr.Format(&buf, c.dialect); err != nil {
+				t.Fatalf("unexpected error: %s", err)
+			}
+			if act := buf.String(); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
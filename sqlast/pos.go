@@ -0,0 +1,57 @@
+package sqlast
+
+import "fmt"
+
+// Pos is a source location: a byte offset paired with the 1-based line and
+// column it corresponds to. The zero value means "unknown position".
+type Pos struct {
+	Offset int
+	Line   int
+	Col    int
+}
+
+func (p Pos) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Col)
+}
+
+// Node is implemented by AST nodes that track the source range they were
+// parsed from.
+type Node interface {
+	Pos() Pos
+	End() Pos
+}
+
+// posRange is embedded into AST node structs to give them a Node
+// implementation. Nothing in this package sets StartPos/EndPos: they hold
+// whatever the code building the tree assigns, and nodes built without them
+// (as in most tests) are left at the zero Pos.
+type posRange struct {
+	StartPos Pos
+	EndPos   Pos
+}
+
+func (p posRange) Pos() Pos { return p.StartPos }
+func (p posRange) End() Pos { return p.EndPos }
+
+// Positions returns the source range of node, or the zero Pos on both ends
+// if node does not track position information.
+func Positions(node ASTNode) (start, end Pos) {
+	if n, ok := node.(Node); ok {
+		return n.Pos(), n.End()
+	}
+	return Pos{}, Pos{}
+}
+
+// PosError is an error tagged with the source position it was raised at.
+type PosError struct {
+	At  Pos
+	Msg string
+}
+
+func (e *PosError) Error() string {
+	return fmt.Sprintf("%s: %s", e.At, e.Msg)
+}
+
+func (e *PosError) Pos() Pos {
+	return e.At
+}
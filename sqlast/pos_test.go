@@ -0,0 +1,90 @@
+package sqlast
+
+import "testing"
+
+func TestPositions(t *testing.T) {
+	cte := &CTE{
+		Alias: NewSQLIdent("regional_sales"),
+		Query: &SQLQuery{
+			posRange: posRange{
+				StartPos: Pos{Offset: 5, Line: 1, Col: 6},
+				EndPos:   Pos{Offset: 40, Line: 1, Col: 41},
+			},
+			Body: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: NewSQLIdentifier(NewSQLIdent("region"))},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("orders"),
+				},
+			},
+		},
+	}
+
+	start, end := Positions(cte.Query)
+	wantStart := Pos{Offset: 5, Line: 1, Col: 6}
+	wantEnd := Pos{Offset: 40, Line: 1, Col: 41}
+	if start != wantStart || end != wantEnd {
+		t.Errorf("must be %v/%v but %v/%v", wantStart, wantEnd, start, end)
+	}
+
+	// A node built without position information (as is common in
+	// hand-written tests) reports the zero Pos on both ends.
+	bare := &SQLSelect{Relation: &Table{Name: NewSQLObjectName("t")}}
+	start, end = Positions(bare)
+	if start != (Pos{}) || end != (Pos{}) {
+		t.Errorf("must be zero Pos but %v/%v", start, end)
+	}
+}
+
+func TestPositions_SubQuery(t *testing.T) {
+	sub := &SQLQuery{
+		posRange: posRange{
+			StartPos: Pos{Offset: 20, Line: 1, Col: 21},
+			EndPos:   Pos{Offset: 45, Line: 1, Col: 46},
+		},
+		Body: &SQLSelect{
+			Projection: []SQLSelectItem{
+				&UnnamedExpression{Node: NewSQLIdentifier(NewSQLIdent("region"))},
+			},
+			Relation: &Table{
+				Name: NewSQLObjectName("top_regions"),
+			},
+		},
+	}
+
+	query := &SQLQuery{
+		Body: &SQLSelect{
+			Projection: []SQLSelectItem{
+				&UnnamedExpression{Node: NewSQLIdentifier(NewSQLIdent("product"))},
+			},
+			Relation: &Table{
+				Name: NewSQLObjectName("orders"),
+			},
+			Selection: &SQLInSubQuery{
+				Expr:     NewSQLIdentifier(NewSQLIdent("region")),
+				SubQuery: sub,
+			},
+		},
+	}
+
+	inSub := query.Body.(*SQLSelect).Selection.(*SQLInSubQuery)
+	start, end := Positions(inSub.SubQuery)
+	wantStart := Pos{Offset: 20, Line: 1, Col: 21}
+	wantEnd := Pos{Offset: 45, Line: 1, Col: 46}
+	if start != wantStart || end != wantEnd {
+		t.Errorf("must be %v/%v but %v/%v", wantStart, wantEnd, start, end)
+	}
+}
+
+func TestPosError(t *testing.T) {
+	err := &PosError{At: Pos{Line: 3, Col: 10}, Msg: "unexpected token"}
+
+	if got := err.Error(); got != "3:10: unexpected token" {
+		t.Errorf("must be %s but %s", "3:10: unexpected token", got)
+	}
+
+	if got := err.Pos(); got != (Pos{Line: 3, Col: 10}) {
+		t.Errorf("must be %v but %v", Pos{Line: 3, Col: 10}, got)
+	}
+}
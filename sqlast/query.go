@@ -1,43 +1,123 @@
 package sqlast
 
 import (
+	"bytes"
 	"fmt"
+	"io"
+	"sort"
 	"strings"
 )
 
 type SQLQuery struct {
+	posRange
 	CTEs    []*CTE
 	Body    SQLSetExpr
 	OrderBy []*SQLOrderByExpr
 	Limit   ASTNode
+	// Offset renders an OFFSET clause, usable alongside or instead of Limit.
+	Offset ASTNode
+	// Fetch renders an ANSI FETCH FIRST/NEXT clause, as an alternative to
+	// Limit for dialects that prefer it (e.g. "OFFSET 10 FETCH FIRST 5 ROWS ONLY").
+	Fetch *FetchExpr
 }
 
 func (s *SQLQuery) Eval() string {
+	var buf bytes.Buffer
+	_ = s.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (s *SQLQuery) Format(out io.Writer, dialect Dialect) error {
 	var q string
 
 	if len(s.CTEs) != 0 {
-		q += "WITH "
+		q += dialect.Keyword("WITH") + " "
 		ctestrs := make([]string, 0, len(s.CTEs))
 		for _, cte := range s.CTEs {
-			ctestrs = append(ctestrs, fmt.Sprintf("%s AS (%s)", cte.Alias.Eval(), cte.Query.Eval()))
+			queryStr, err := formatEval(dialect, cte.Query)
+			if err != nil {
+				return err
+			}
+			ctestrs = append(ctestrs, fmt.Sprintf("%s %s (%s)", quoteIdent(dialect, cte.Alias), dialect.Keyword("AS"), queryStr))
 		}
 		q += strings.Join(ctestrs, ", ") + " "
 	}
 
-	q += s.Body.Eval()
+	bodyStr, err := formatEval(dialect, s.Body)
+	if err != nil {
+		return err
+	}
+
+	if dialect.UsesTopClause() && s.Limit != nil {
+		top, err := dialect.FormatLimit(s.Limit)
+		if err != nil {
+			return err
+		}
+		bodyStr = injectTopClause(bodyStr, dialect, top)
+	}
+
+	q += bodyStr
 
 	if len(s.OrderBy) != 0 {
-		q += fmt.Sprintf(" ORDER BY %s", commaSeparatedString(s.OrderBy))
+		orderNodes := make([]ASTNode, len(s.OrderBy))
+		for i, o := range s.OrderBy {
+			orderNodes[i] = o
+		}
+		orderStr, err := formatCommaSeparated(dialect, orderNodes)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("ORDER BY"), orderStr)
+	}
+
+	if !dialect.UsesTopClause() && s.Limit != nil {
+		limitStr, err := dialect.FormatLimit(s.Limit)
+		if err != nil {
+			return err
+		}
+		q += " " + limitStr
 	}
 
-	if s.Limit != nil {
-		q += fmt.Sprintf(" LIMIT %s", s.Limit.Eval())
+	if s.Offset != nil {
+		offsetStr, err := formatEval(dialect, s.Offset)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("OFFSET"), offsetStr)
+	}
+
+	if s.Fetch != nil {
+		fetchStr, err := formatEval(dialect, s.Fetch)
+		if err != nil {
+			return err
+		}
+		q += " " + fetchStr
+	}
+
+	_, err = io.WriteString(out, q)
+	return err
+}
+
+// injectTopClause inserts a TOP-style limit fragment immediately after the
+// SELECT keyword (and the DISTINCT keyword, if present) of a rendered query
+// body, since TOP dialects (MSSQL) place the limit before the projection
+// instead of after the query: "SELECT [DISTINCT] TOP n ...".
+func injectTopClause(body string, dialect Dialect, top string) string {
+	prefix := dialect.Keyword("SELECT") + " "
+	if !strings.HasPrefix(body, prefix) {
+		return body
 	}
+	rest := body[len(prefix):]
 
-	return q
+	distinctPrefix := dialect.Keyword("DISTINCT") + " "
+	if strings.HasPrefix(rest, distinctPrefix) {
+		return prefix + distinctPrefix + top + " " + rest[len(distinctPrefix):]
+	}
+	return prefix + top + " " + rest
 }
 
 type CTE struct {
+	posRange
 	Alias *SQLIdent
 	Query *SQLQuery
 }
@@ -48,22 +128,47 @@ type SQLSetExpr interface {
 }
 
 type SelectExpr struct {
+	posRange
 	Select *SQLSelect
 }
 
 func (s *SelectExpr) Eval() string {
-	return s.Select.Eval()
+	var buf bytes.Buffer
+	_ = s.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (s *SelectExpr) Format(out io.Writer, dialect Dialect) error {
+	selStr, err := formatEval(dialect, s.Select)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, selStr)
+	return err
 }
 
 type QueryExpr struct {
+	posRange
 	Query *SQLQuery
 }
 
 func (q *QueryExpr) Eval() string {
-	return fmt.Sprintf("(%s)", q.Query.Eval())
+	var buf bytes.Buffer
+	_ = q.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (q *QueryExpr) Format(out io.Writer, dialect Dialect) error {
+	queryStr, err := formatEval(dialect, q.Query)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "(%s)", queryStr)
+	return err
 }
 
 type SetOperationExpr struct {
+	posRange
 	Op    SQLSetOperator
 	All   bool
 	Left  SQLSetExpr
@@ -71,11 +176,32 @@ type SetOperationExpr struct {
 }
 
 func (s *SetOperationExpr) Eval() string {
+	var buf bytes.Buffer
+	_ = s.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (s *SetOperationExpr) Format(out io.Writer, dialect Dialect) error {
+	leftStr, err := formatEval(dialect, s.Left)
+	if err != nil {
+		return err
+	}
+	opStr, err := formatEval(dialect, s.Op)
+	if err != nil {
+		return err
+	}
+	rightStr, err := formatEval(dialect, s.Right)
+	if err != nil {
+		return err
+	}
+
 	var allStr string
 	if s.All {
-		allStr = " ALL"
+		allStr = " " + dialect.Keyword("ALL")
 	}
-	return fmt.Sprintf("%s %s%s %s", s.Left.Eval(), s.Op.Eval(), allStr, s.Right.Eval())
+
+	_, err = fmt.Fprintf(out, "%s %s%s %s", leftStr, opStr, allStr, rightStr)
+	return err
 }
 
 /** SQLSetOperator **/
@@ -83,29 +209,49 @@ type SQLSetOperator interface {
 	ASTNode
 }
 
-type UnionOperator struct{}
+type UnionOperator struct {
+	posRange
+}
 
 func (UnionOperator) Eval() string {
 	return "UNION"
 }
 
+func (UnionOperator) Format(out io.Writer, dialect Dialect) error {
+	_, err := io.WriteString(out, dialect.Keyword("UNION"))
+	return err
+}
+
 type ExceptOperator struct {
+	posRange
 }
 
 func (ExceptOperator) Eval() string {
 	return "EXCEPT"
 }
 
+func (ExceptOperator) Format(out io.Writer, dialect Dialect) error {
+	_, err := io.WriteString(out, dialect.Keyword("EXCEPT"))
+	return err
+}
+
 type IntersectOperator struct {
+	posRange
 }
 
 func (IntersectOperator) Eval() string {
 	return "INTERSECT"
 }
 
+func (IntersectOperator) Format(out io.Writer, dialect Dialect) error {
+	_, err := io.WriteString(out, dialect.Keyword("INTERSECT"))
+	return err
+}
+
 /** SQLSetOperator end **/
 
 type SQLSelect struct {
+	posRange
 	Distinct   bool
 	Projection []SQLSelectItem
 	Relation   TableFactor
@@ -113,36 +259,104 @@ type SQLSelect struct {
 	Selection  ASTNode
 	GroupBy    []ASTNode
 	Having     ASTNode
+	// Windows holds named window definitions declared via WINDOW name AS
+	// (...), referenced by an OVER clause elsewhere in the query.
+	Windows map[string]*Window
+	// Qualify filters rows by the result of a window function, as supported
+	// by Snowflake/BigQuery/DuckDB.
+	Qualify ASTNode
 }
 
 func (s *SQLSelect) Eval() string {
-	q := "SELECT "
+	var buf bytes.Buffer
+	_ = s.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (s *SQLSelect) Format(out io.Writer, dialect Dialect) error {
+	q := dialect.Keyword("SELECT") + " "
 	if s.Distinct {
-		q += "DISTINCT "
+		q += dialect.Keyword("DISTINCT") + " "
+	}
+
+	projNodes := make([]ASTNode, len(s.Projection))
+	for i, p := range s.Projection {
+		projNodes[i] = p
 	}
-	q += commaSeparatedString(s.Projection)
+	projStr, err := formatCommaSeparated(dialect, projNodes)
+	if err != nil {
+		return err
+	}
+	q += projStr
 
 	if s.Relation != nil {
-		q += fmt.Sprintf(" FROM %s", s.Relation.Eval())
+		relStr, err := formatEval(dialect, s.Relation)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("FROM"), relStr)
 	}
 
 	for _, j := range s.Joins {
-		q += j.Eval()
+		joinStr, err := formatEval(dialect, j)
+		if err != nil {
+			return err
+		}
+		q += joinStr
 	}
 
 	if s.Selection != nil {
-		q += fmt.Sprintf(" WHERE %s", s.Selection.Eval())
+		selStr, err := formatEval(dialect, s.Selection)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("WHERE"), selStr)
 	}
 
 	if len(s.GroupBy) != 0 {
-		q += fmt.Sprintf(" GROUP BY %s", commaSeparatedString(s.GroupBy))
+		groupStr, err := formatCommaSeparated(dialect, s.GroupBy)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("GROUP BY"), groupStr)
 	}
 
 	if s.Having != nil {
-		q += fmt.Sprintf(" HAVING %s", s.Having.Eval())
+		havingStr, err := formatEval(dialect, s.Having)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("HAVING"), havingStr)
+	}
+
+	if len(s.Windows) != 0 {
+		names := make([]string, 0, len(s.Windows))
+		for name := range s.Windows {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		defs := make([]string, 0, len(names))
+		for _, name := range names {
+			winStr, err := formatEval(dialect, s.Windows[name])
+			if err != nil {
+				return err
+			}
+			defs = append(defs, fmt.Sprintf("%s %s (%s)", name, dialect.Keyword("AS"), winStr))
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("WINDOW"), strings.Join(defs, ", "))
+	}
+
+	if s.Qualify != nil {
+		qualifyStr, err := formatEval(dialect, s.Qualify)
+		if err != nil {
+			return err
+		}
+		q += fmt.Sprintf(" %s %s", dialect.Keyword("QUALIFY"), qualifyStr)
 	}
 
-	return q
+	_, err = io.WriteString(out, q)
+	return err
 }
 
 /** TableFactor **/
@@ -151,6 +365,7 @@ type TableFactor interface {
 }
 
 type Table struct {
+	posRange
 	Name      *SQLObjectName
 	Alias     *SQLIdent
 	Args      []ASTNode
@@ -158,30 +373,64 @@ type Table struct {
 }
 
 func (t *Table) Eval() string {
+	var buf bytes.Buffer
+	_ = t.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (t *Table) Format(out io.Writer, dialect Dialect) error {
 	s := t.Name.Eval()
 	if len(t.Args) != 0 {
-		s = fmt.Sprintf("%s(%s)", s, commaSeparatedString(t.Args))
+		argsStr, err := formatCommaSeparated(dialect, t.Args)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf("%s(%s)", s, argsStr)
 	}
 	if t.Alias != nil {
-		s = fmt.Sprintf("%s AS %s", s, t.Alias.Eval())
+		s = fmt.Sprintf("%s %s %s", s, dialect.Keyword("AS"), quoteIdent(dialect, t.Alias))
 	}
 	if len(t.WithHints) != 0 {
-		s = fmt.Sprintf("%s WITH (%s)", s, commaSeparatedString(t.WithHints))
+		hintsStr, err := formatCommaSeparated(dialect, t.WithHints)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf("%s %s (%s)", s, dialect.Keyword("WITH"), hintsStr)
 	}
-	return s
+	_, err := io.WriteString(out, s)
+	return err
 }
 
 type Derived struct {
+	posRange
+	// Lateral indicates the subquery is introduced with LATERAL, allowing it
+	// to reference columns from preceding FROM items.
+	Lateral  bool
 	SubQuery *SQLQuery
 	Alias    *SQLIdent
 }
 
 func (d *Derived) Eval() string {
-	s := d.SubQuery.Eval()
+	var buf bytes.Buffer
+	_ = d.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (d *Derived) Format(out io.Writer, dialect Dialect) error {
+	var s string
+	if d.Lateral {
+		s += dialect.Keyword("LATERAL") + " "
+	}
+	subStr, err := formatEval(dialect, d.SubQuery)
+	if err != nil {
+		return err
+	}
+	s += fmt.Sprintf("(%s)", subStr)
 	if d.Alias != nil {
-		s = fmt.Sprintf("%s AS %s", s, d.Alias.Eval())
+		s = fmt.Sprintf("%s %s %s", s, dialect.Keyword("AS"), quoteIdent(dialect, d.Alias))
 	}
-	return s
+	_, err = io.WriteString(out, s)
+	return err
 }
 
 /** TableFactor end **/
@@ -192,6 +441,7 @@ type SQLSelectItem interface {
 }
 
 type UnnamedExpression struct {
+	posRange
 	Node ASTNode
 }
 
@@ -199,7 +449,17 @@ func (u *UnnamedExpression) Eval() string {
 	return u.Node.Eval()
 }
 
+func (u *UnnamedExpression) Format(out io.Writer, dialect Dialect) error {
+	s, err := formatEval(dialect, u.Node)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(out, s)
+	return err
+}
+
 type ExpressionWithAlias struct {
+	posRange
 	Expr  ASTNode
 	Alias *SQLIdent
 }
@@ -208,8 +468,18 @@ func (e *ExpressionWithAlias) Eval() string {
 	return fmt.Sprintf("%s AS %s", e.Expr.Eval(), e.Alias.Eval())
 }
 
+func (e *ExpressionWithAlias) Format(out io.Writer, dialect Dialect) error {
+	exprStr, err := formatEval(dialect, e.Expr)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s %s %s", exprStr, dialect.Keyword("AS"), quoteIdent(dialect, e.Alias))
+	return err
+}
+
 // schema.*
 type QualifiedWildcard struct {
+	posRange
 	Prefix *SQLObjectName
 }
 
@@ -217,37 +487,95 @@ func (q *QualifiedWildcard) Eval() string {
 	return fmt.Sprintf("%s.*", q.Prefix.Eval())
 }
 
-type Wildcard struct{}
+func (q *QualifiedWildcard) Format(out io.Writer, dialect Dialect) error {
+	_, err := fmt.Fprintf(out, "%s.*", q.Prefix.Eval())
+	return err
+}
+
+type Wildcard struct {
+	posRange
+}
 
 func (w *Wildcard) Eval() string {
 	return "*"
 }
 
+func (w *Wildcard) Format(out io.Writer, dialect Dialect) error {
+	_, err := io.WriteString(out, "*")
+	return err
+}
+
 /** SQLSelectItem end **/
 
 type Join struct {
+	posRange
 	Relation TableFactor
 	Op       JoinOperator
 	Constant JoinConstant
 }
 
 func (j *Join) Eval() string {
+	var buf bytes.Buffer
+	_ = j.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (j *Join) Format(out io.Writer, dialect Dialect) error {
+	relStr, err := formatEval(dialect, j.Relation)
+	if err != nil {
+		return err
+	}
+
+	var s string
 	switch j.Op {
 	case Inner:
-		return fmt.Sprintf(" %sJOIN %s%s", j.Constant.Prefix(), j.Relation.Eval(), j.Constant.Suffix())
+		prefix := j.Constant.Prefix(dialect)
+		suffix, err := j.Constant.Suffix(dialect)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf(" %s%s %s%s", prefix, dialect.Keyword("JOIN"), relStr, suffix)
 	case Cross:
-		return fmt.Sprintf(" CROSS JOIN%s", j.Relation.Eval())
+		s = fmt.Sprintf(" %s %s %s", dialect.Keyword("CROSS"), dialect.Keyword("JOIN"), relStr)
 	case Implicit:
-		return fmt.Sprintf(", %s", j.Relation.Eval())
+		s = fmt.Sprintf(", %s", relStr)
 	case LeftOuter:
-		return fmt.Sprintf(" %sLEFT JOIN %s%s", j.Constant.Prefix(), j.Relation.Eval(), j.Constant.Suffix())
+		prefix := j.Constant.Prefix(dialect)
+		suffix, err := j.Constant.Suffix(dialect)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf(" %s%s %s%s", prefix, dialect.Keyword("LEFT JOIN"), relStr, suffix)
 	case RightOuter:
-		return fmt.Sprintf(" %sRIGHT JOIN %s%s", j.Constant.Prefix(), j.Relation.Eval(), j.Constant.Suffix())
+		prefix := j.Constant.Prefix(dialect)
+		suffix, err := j.Constant.Suffix(dialect)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf(" %s%s %s%s", prefix, dialect.Keyword("RIGHT JOIN"), relStr, suffix)
 	case FullOuter:
-		return fmt.Sprintf(" %sFULL JOIN %s%s", j.Constant.Prefix(), j.Relation.Eval(), j.Constant.Suffix())
+		prefix := j.Constant.Prefix(dialect)
+		suffix, err := j.Constant.Suffix(dialect)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf(" %s%s %s%s", prefix, dialect.Keyword("FULL JOIN"), relStr, suffix)
+	case CrossApply:
+		if !dialect.SupportsApply() {
+			return fmt.Errorf("sqlast: dialect does not support CROSS APPLY joins")
+		}
+		s = fmt.Sprintf(" %s %s", dialect.Keyword("CROSS APPLY"), relStr)
+	case OuterApply:
+		if !dialect.SupportsApply() {
+			return fmt.Errorf("sqlast: dialect does not support OUTER APPLY joins")
+		}
+		s = fmt.Sprintf(" %s %s", dialect.Keyword("OUTER APPLY"), relStr)
 	default:
-		return ""
+		s = ""
 	}
+
+	_, err = io.WriteString(out, s)
+	return err
 }
 
 type JoinOperator int
@@ -259,66 +587,103 @@ const (
 	FullOuter
 	Implicit
 	Cross
+	// CrossApply and OuterApply are T-SQL (MSSQL) extensions, rejected by
+	// Join.Format on any dialect with SupportsApply() == false.
+	//
+	// This is a Format-time gate only, not a parser-level one: this tree has
+	// no parser.go to reject APPLY while parsing other dialects' SQL, so a
+	// caller that builds a CrossApply/OuterApply Join by hand and renders it
+	// with Eval() (which always uses AnsiDialect and discards the Format
+	// error) gets an empty string with no error, and so does the Eval() of
+	// any SQLSelect or SQLQuery containing that Join.
+	// Callers who need the rejection must call Format with the intended
+	// dialect directly and check the returned error.
+	CrossApply
+	OuterApply
 )
 
 /** JoinConstant **/
 type JoinConstant interface {
-	Prefix() string
-	Suffix() string
+	Prefix(dialect Dialect) string
+	Suffix(dialect Dialect) (string, error)
 }
 
 type OnJoinConstant struct {
+	posRange
 	Node ASTNode
 }
 
-func (*OnJoinConstant) Prefix() string {
+func (*OnJoinConstant) Prefix(dialect Dialect) string {
 	return ""
 }
 
-func (o *OnJoinConstant) Suffix() string {
-	return fmt.Sprintf(" ON %s", o.Node.Eval())
+func (o *OnJoinConstant) Suffix(dialect Dialect) (string, error) {
+	s, err := formatEval(dialect, o.Node)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(" %s %s", dialect.Keyword("ON"), s), nil
 }
 
 type UsingConstant struct {
+	posRange
 	Idents []*SQLIdent
 }
 
-func (*UsingConstant) Prefix() string {
+func (*UsingConstant) Prefix(dialect Dialect) string {
 	return ""
 }
 
-func (u *UsingConstant) Suffix() string {
+func (u *UsingConstant) Suffix(dialect Dialect) (string, error) {
 	var str []string
 	for _, i := range u.Idents {
-		str = append(str, string(*i))
+		str = append(str, quoteIdent(dialect, i))
 	}
-	return fmt.Sprintf(" USING(%s)", strings.Join(str, ", "))
+	return fmt.Sprintf(" %s(%s)", dialect.Keyword("USING"), strings.Join(str, ", ")), nil
 }
 
 type NaturalConstant struct {
+	posRange
 }
 
-func (*NaturalConstant) Prefix() string {
-	return "NATURAL "
+func (*NaturalConstant) Prefix(dialect Dialect) string {
+	return dialect.Keyword("NATURAL") + " "
 }
 
-func (*NaturalConstant) Suffix() string {
-	return ""
+func (*NaturalConstant) Suffix(dialect Dialect) (string, error) {
+	return "", nil
 }
 
 /** JoinConstant end **/
 
 type SQLOrderByExpr struct {
+	posRange
 	Expr ASTNode
 	ASC  *bool
 }
 
 func (s *SQLOrderByExpr) Eval() string {
-	if s.ASC == nil {
-		return s.Expr.Eval()
+	var buf bytes.Buffer
+	_ = s.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (s *SQLOrderByExpr) Format(out io.Writer, dialect Dialect) error {
+	exprStr, err := formatEval(dialect, s.Expr)
+	if err != nil {
+		return err
 	}
-	if *s.ASC {
-		return fmt.Sprintf("%s ASC", s.Expr.Eval())
+
+	var res string
+	switch {
+	case s.ASC == nil:
+		res = exprStr
+	case *s.ASC:
+		res = fmt.Sprintf("%s ASC", exprStr)
+	default:
+		res = fmt.Sprintf("%s DESC", exprStr)
 	}
-	return fmt.Sprintf("%s DESC", s.Expr.Eval())
+
+	_, err = io.WriteString(out, res)
+	return err
 }
@@ -109,7 +109,41 @@ func TestSQLSelect_Eval(t *testing.T) {
 					},
 				},
 			},
-			out: "SELECT COUNT(t1.id) AS c FROM test_table AS t1 LEFT JOIN test_table2 AS t2 ON t1.id = t2.test_table_id",
+			out: `SELECT COUNT(t1.id) AS "c" FROM test_table AS "t1" LEFT JOIN test_table2 AS "t2" ON t1.id = t2.test_table_id`,
+		},
+		{
+			name: "lateral derived table",
+			in: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{
+						Node: &SQLWildcard{},
+					},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("t1"),
+				},
+				Joins: []*Join{
+					{
+						Relation: &Derived{
+							Lateral: true,
+							SubQuery: &SQLQuery{
+								Body: &SQLSelect{
+									Projection: []SQLSelectItem{
+										&UnnamedExpression{Node: &SQLWildcard{}},
+									},
+									Relation: &Table{
+										Name: NewSQLObjectName("t2"),
+									},
+								},
+							},
+							Alias: NewSQLIdent("sub"),
+						},
+						Op:       Cross,
+						Constant: &NaturalConstant{},
+					},
+				},
+			},
+			out: `SELECT * FROM t1 CROSS JOIN LATERAL (SELECT * FROM t2) AS "sub"`,
 		},
 		{
 			name: "group by",
@@ -238,10 +272,10 @@ func TestSQLQuery_Eval(t *testing.T) {
 					GroupBy: []ASTNode{NewSQLIdentifier(NewSQLIdent("region")), NewSQLIdentifier(NewSQLIdent("product"))},
 				},
 			},
-			out: "WITH regional_sales AS (" +
-				"SELECT region, SUM(amount) AS total_sales " +
+			out: `WITH "regional_sales" AS (` +
+				`SELECT region, SUM(amount) AS "total_sales" ` +
 				"FROM orders GROUP BY region) " +
-				"SELECT product, SUM(quantity) AS product_units " +
+				`SELECT product, SUM(quantity) AS "product_units" ` +
 				"FROM orders " +
 				"WHERE region IN (SELECT region FROM top_regions) " +
 				"GROUP BY region, product",
@@ -282,7 +316,7 @@ func TestSQLQuery_Eval(t *testing.T) {
 				},
 				Limit: NewLongValue(100),
 			},
-			out: "SELECT product, SUM(quantity) AS product_units " +
+			out: `SELECT product, SUM(quantity) AS "product_units" ` +
 				"FROM orders " +
 				"WHERE region IN (SELECT region FROM top_regions) " +
 				"ORDER BY product_units LIMIT 100",
@@ -0,0 +1,472 @@
+// Package sema performs a basic semantic pass over a parsed sqlast.SQLQuery:
+// it expands wildcard projections, resolves GROUP BY/HAVING aliases,
+// rejects duplicate output column names, and records which FROM tables
+// the projection, WHERE and HAVING expressions depend on.
+package sema
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/isgasho/xsqlparser/sqlast"
+)
+
+// Schema describes the columns available on each table the query may
+// reference, keyed by table name.
+type Schema map[string][]string
+
+// ErrorCode classifies the kind of semantic error encountered.
+type ErrorCode int
+
+const (
+	ErrUnknownColumn ErrorCode = iota
+	ErrAmbiguousColumn
+	ErrDupFieldName
+	ErrGroupOnNonGroupField
+)
+
+// Error is a semantic error tagged with a SQLSTATE code and, where
+// available, the source position of the offending node.
+type Error struct {
+	Code     ErrorCode
+	SQLState string
+	Msg      string
+	At       sqlast.Pos
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %s (SQLSTATE %s)", e.At, e.Msg, e.SQLState)
+}
+
+func (e *Error) Pos() sqlast.Pos {
+	return e.At
+}
+
+func newError(code ErrorCode, sqlState string, node sqlast.ASTNode, format string, args ...interface{}) *Error {
+	start, _ := sqlast.Positions(node)
+	return &Error{
+		Code:     code,
+		SQLState: sqlState,
+		Msg:      fmt.Sprintf(format, args...),
+		At:       start,
+	}
+}
+
+// Resolved is the result of resolving a query: its (possibly rewritten)
+// projection, and the set of FROM tables that each projection expression
+// and the WHERE and HAVING predicates depend on.
+type Resolved struct {
+	Query      *sqlast.SQLQuery
+	Projection []sqlast.SQLSelectItem
+	// Tables maps each FROM-scope table/alias to the columns it exposes.
+	Tables map[string][]string
+	// Deps maps an expression node (by identity) to the table names it
+	// references. Only each projection expression, the WHERE predicate and
+	// the HAVING predicate have an entry; their sub-expressions, GROUP BY
+	// keys and join conditions do not.
+	Deps map[sqlast.ASTNode][]string
+}
+
+// scope holds the tables visible in the query's FROM clause, keyed by alias
+// (or table name, if unaliased). Resolve builds a single scope, so
+// references to the tables of an enclosing query are not resolved.
+type scope struct {
+	tables map[string][]string
+	// common holds column names merged by a USING(...) or NATURAL join: an
+	// unqualified reference to one of these names resolves to every table
+	// that exposes it rather than being rejected as ambiguous.
+	common map[string]bool
+}
+
+func newScope() *scope {
+	return &scope{tables: map[string][]string{}, common: map[string]bool{}}
+}
+
+// Resolve expands wildcards, resolves GROUP BY/HAVING aliases, checks for
+// duplicate output names, and records table dependencies for query against
+// schema. Only a single, non-set-operation SELECT body is supported; CTEs
+// are registered into the schema (using their own projection's output
+// names as columns) so the outer query can reference them, but are not
+// themselves re-validated.
+func Resolve(query *sqlast.SQLQuery, schema Schema) (*Resolved, error) {
+	effectiveSchema := schema
+	if len(query.CTEs) != 0 {
+		effectiveSchema = make(Schema, len(schema)+len(query.CTEs))
+		for k, v := range schema {
+			effectiveSchema[k] = v
+		}
+		for _, cte := range query.CTEs {
+			effectiveSchema[string(*cte.Alias)] = cteColumns(cte.Query)
+		}
+	}
+
+	sel, ok := query.Body.(*sqlast.SelectExpr)
+	if !ok {
+		return &Resolved{Query: query, Tables: map[string][]string{}, Deps: map[sqlast.ASTNode][]string{}}, nil
+	}
+
+	sc := newScope()
+	collectFromScope(sc, sel.Select.Relation, effectiveSchema)
+	for _, j := range sel.Select.Joins {
+		collectFromScope(sc, j.Relation, effectiveSchema)
+		collectJoinConstantScope(sc, j.Constant)
+	}
+
+	projection := expandWildcards(sel.Select.Projection, sc)
+
+	aliasExprs := map[string]sqlast.ASTNode{}
+	for _, item := range projection {
+		if aliased, ok := item.(*sqlast.ExpressionWithAlias); ok {
+			aliasExprs[string(*aliased.Alias)] = aliased.Expr
+		}
+	}
+
+	for i, g := range sel.Select.GroupBy {
+		sel.Select.GroupBy[i] = resolveAliasInExpr(g, aliasExprs, sc)
+	}
+	if sel.Select.Having != nil {
+		sel.Select.Having = resolveAliasInExpr(sel.Select.Having, aliasExprs, sc)
+	}
+
+	if err := checkDuplicateNames(projection); err != nil {
+		return nil, err
+	}
+
+	if err := checkGroupBy(projection, sel.Select.GroupBy); err != nil {
+		return nil, err
+	}
+
+	deps := map[sqlast.ASTNode][]string{}
+	recordDeps := func(node sqlast.ASTNode) error {
+		if node == nil {
+			return nil
+		}
+		tables, err := dependencies(node, sc)
+		if err != nil {
+			return err
+		}
+		deps[node] = tables
+		return nil
+	}
+
+	for _, item := range projection {
+		var expr sqlast.ASTNode
+		switch n := item.(type) {
+		case *sqlast.UnnamedExpression:
+			expr = n.Node
+		case *sqlast.ExpressionWithAlias:
+			expr = n.Expr
+		}
+		if err := recordDeps(expr); err != nil {
+			return nil, err
+		}
+	}
+	if err := recordDeps(sel.Select.Selection); err != nil {
+		return nil, err
+	}
+	if err := recordDeps(sel.Select.Having); err != nil {
+		return nil, err
+	}
+
+	return &Resolved{
+		Query:      query,
+		Projection: projection,
+		Tables:     sc.tables,
+		Deps:       deps,
+	}, nil
+}
+
+// cteColumns derives the output column names of a CTE body by inspecting
+// its projection: aliased columns use their alias, bare compound
+// identifiers use their last segment, everything else falls back to the
+// expression's rendered text.
+func cteColumns(q *sqlast.SQLQuery) []string {
+	sel, ok := q.Body.(*sqlast.SelectExpr)
+	if !ok {
+		return nil
+	}
+	cols := make([]string, 0, len(sel.Select.Projection))
+	for _, item := range sel.Select.Projection {
+		cols = append(cols, outputName(item))
+	}
+	return cols
+}
+
+func collectFromScope(sc *scope, relation sqlast.TableFactor, schema Schema) {
+	switch t := relation.(type) {
+	case *sqlast.Table:
+		name := t.Name.Eval()
+		alias := name
+		if t.Alias != nil {
+			alias = string(*t.Alias)
+		}
+		sc.tables[alias] = schema[name]
+	case *sqlast.Derived:
+		if t.Alias != nil {
+			sc.tables[string(*t.Alias)] = cteColumns(t.SubQuery)
+		}
+	}
+}
+
+// collectJoinConstantScope records the columns a USING or NATURAL join
+// constraint merges across its two sides, so dependencies treats an
+// unqualified reference to one of them as shared rather than ambiguous.
+func collectJoinConstantScope(sc *scope, constant sqlast.JoinConstant) {
+	switch c := constant.(type) {
+	case *sqlast.UsingConstant:
+		for _, id := range c.Idents {
+			sc.common[string(*id)] = true
+		}
+	case *sqlast.NaturalConstant:
+		counts := map[string]int{}
+		for _, cols := range sc.tables {
+			for _, col := range cols {
+				counts[col]++
+			}
+		}
+		for col, n := range counts {
+			if n > 1 {
+				sc.common[col] = true
+			}
+		}
+	}
+}
+
+// expandWildcards replaces bare Wildcard and QualifiedWildcard projection
+// items with an explicit SQLCompoundIdentifier per column, in FROM-scope
+// order (qualified wildcards are expanded against their named table only).
+func expandWildcards(items []sqlast.SQLSelectItem, sc *scope) []sqlast.SQLSelectItem {
+	out := make([]sqlast.SQLSelectItem, 0, len(items))
+	for _, item := range items {
+		switch n := item.(type) {
+		case *sqlast.UnnamedExpression:
+			if _, ok := n.Node.(*sqlast.Wildcard); ok {
+				out = append(out, expandAllTables(sc)...)
+				continue
+			}
+		case *sqlast.QualifiedWildcard:
+			table := n.Prefix.Eval()
+			out = append(out, expandTable(table, sc.tables[table])...)
+			continue
+		}
+		out = append(out, item)
+	}
+	return out
+}
+
+func expandAllTables(sc *scope) []sqlast.SQLSelectItem {
+	names := make([]string, 0, len(sc.tables))
+	for name := range sc.tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out []sqlast.SQLSelectItem
+	for _, name := range names {
+		out = append(out, expandTable(name, sc.tables[name])...)
+	}
+	return out
+}
+
+func expandTable(table string, columns []string) []sqlast.SQLSelectItem {
+	out := make([]sqlast.SQLSelectItem, 0, len(columns))
+	for _, col := range columns {
+		out = append(out, &sqlast.UnnamedExpression{
+			Node: &sqlast.SQLCompoundIdentifier{
+				Idents: []*sqlast.SQLIdent{sqlast.NewSQLIdent(table), sqlast.NewSQLIdent(col)},
+			},
+		})
+	}
+	return out
+}
+
+// resolveAliasInExpr replaces bare identifiers matching a SELECT-list alias
+// with the aliased expression, implementing GROUP BY/HAVING alias
+// resolution. A name that is both a projection alias and a genuine FROM
+// column is left unresolved: the real column takes precedence over the
+// alias, matching the common (e.g. Postgres) GROUP BY ambiguity rule, and
+// resolving it to the alias would silently group by the wrong expression.
+// Alias substitution walks expression trees itself rather than using
+// sqlast.Rewrite, which would also descend into nested subqueries: it
+// descends only into SQLBinaryExpr operands and SQLFunction arguments, and
+// identifiers inside any other expression kind are left as they are.
+func resolveAliasInExpr(expr sqlast.ASTNode, aliasExprs map[string]sqlast.ASTNode, sc *scope) sqlast.ASTNode {
+	if expr == nil {
+		return nil
+	}
+	switch n := expr.(type) {
+	case *sqlast.SQLIdentifier:
+		name := n.Eval()
+		if isColumnName(name, sc) {
+			return n
+		}
+		if target, found := aliasExprs[name]; found {
+			return target
+		}
+		return n
+	case *sqlast.SQLBinaryExpr:
+		n.Left = resolveAliasInExpr(n.Left, aliasExprs, sc)
+		n.Right = resolveAliasInExpr(n.Right, aliasExprs, sc)
+		return n
+	case *sqlast.SQLFunction:
+		for i, a := range n.Args {
+			n.Args[i] = resolveAliasInExpr(a, aliasExprs, sc)
+		}
+		return n
+	default:
+		return expr
+	}
+}
+
+// isColumnName reports whether name is a genuine column of some FROM-scope
+// table, as opposed to only a projection alias.
+func isColumnName(name string, sc *scope) bool {
+	for _, cols := range sc.tables {
+		for _, c := range cols {
+			if c == name {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func outputName(item sqlast.SQLSelectItem) string {
+	switch n := item.(type) {
+	case *sqlast.ExpressionWithAlias:
+		return string(*n.Alias)
+	case *sqlast.UnnamedExpression:
+		if ci, ok := n.Node.(*sqlast.SQLCompoundIdentifier); ok && len(ci.Idents) != 0 {
+			return string(*ci.Idents[len(ci.Idents)-1])
+		}
+		return n.Node.Eval()
+	default:
+		return item.Eval()
+	}
+}
+
+func checkDuplicateNames(projection []sqlast.SQLSelectItem) error {
+	seen := map[string]bool{}
+	for _, item := range projection {
+		name := outputName(item)
+		if seen[name] {
+			return newError(ErrDupFieldName, "42S21", item, "duplicate output column name %q", name)
+		}
+		seen[name] = true
+	}
+	return nil
+}
+
+// checkGroupBy rejects bare column references in the projection that are
+// neither a GROUP BY key nor (by construction, since this package does not
+// identify aggregate functions) anything else, matching the simple
+// "SELECT a, b FROM t GROUP BY a" MySQL/Postgres error.
+func checkGroupBy(projection []sqlast.SQLSelectItem, groupBy []sqlast.ASTNode) error {
+	if len(groupBy) == 0 {
+		return nil
+	}
+
+	keys := map[string]bool{}
+	for _, g := range groupBy {
+		keys[g.Eval()] = true
+	}
+
+	for _, item := range projection {
+		var expr sqlast.ASTNode
+		switch n := item.(type) {
+		case *sqlast.UnnamedExpression:
+			expr = n.Node
+		case *sqlast.ExpressionWithAlias:
+			expr = n.Expr
+		default:
+			continue
+		}
+
+		switch expr.(type) {
+		case *sqlast.SQLCompoundIdentifier, *sqlast.SQLIdentifier:
+			if !keys[expr.Eval()] {
+				return newError(ErrGroupOnNonGroupField, "42803", expr,
+					"column %q must appear in the GROUP BY clause or be used in an aggregate function", expr.Eval())
+			}
+		}
+	}
+	return nil
+}
+
+// walkExpr visits node and, for the expression shapes this package needs to
+// understand, its descendants. Dependency tracking walks expression trees
+// itself rather than using sqlast.Inspect, which would also descend into
+// nested subqueries. SQLInSubQuery and SQLExists carry a nested SQLQuery with its
+// own FROM scope, so walkExpr does not descend into it: only SQLInSubQuery's
+// left-hand Expr is a dependency of the outer query; EXISTS is treated as
+// having none, since correlated references into the outer scope are not
+// modeled here.
+func walkExpr(node sqlast.ASTNode, visit func(sqlast.ASTNode)) {
+	if node == nil {
+		return
+	}
+	visit(node)
+	switch n := node.(type) {
+	case *sqlast.SQLBinaryExpr:
+		walkExpr(n.Left, visit)
+		walkExpr(n.Right, visit)
+	case *sqlast.SQLFunction:
+		for _, a := range n.Args {
+			walkExpr(a, visit)
+		}
+	case *sqlast.SQLInSubQuery:
+		walkExpr(n.Expr, visit)
+	case *sqlast.SQLExists:
+		// No outer-scope expression to walk; the subquery has its own scope.
+	}
+}
+
+// dependencies walks expr and returns the set of FROM-scope table names it
+// references, erroring on unknown or ambiguous bare column references.
+func dependencies(expr sqlast.ASTNode, sc *scope) ([]string, error) {
+	deps := map[string]bool{}
+	var walkErr error
+
+	walkExpr(expr, func(node sqlast.ASTNode) {
+		if walkErr != nil {
+			return
+		}
+		switch n := node.(type) {
+		case *sqlast.SQLCompoundIdentifier:
+			if len(n.Idents) != 0 {
+				deps[string(*n.Idents[0])] = true
+			}
+		case *sqlast.SQLIdentifier:
+			name := n.Eval()
+			var matches []string
+			for table, cols := range sc.tables {
+				for _, c := range cols {
+					if c == name {
+						matches = append(matches, table)
+						break
+					}
+				}
+			}
+			switch {
+			case len(matches) == 0:
+				walkErr = newError(ErrUnknownColumn, "42703", node, "unknown column %q", name)
+			case len(matches) == 1 || sc.common[name]:
+				for _, table := range matches {
+					deps[table] = true
+				}
+			default:
+				walkErr = newError(ErrAmbiguousColumn, "42702", node, "ambiguous column %q", name)
+			}
+		}
+	})
+	if walkErr != nil {
+		return nil, walkErr
+	}
+
+	names := make([]string, 0, len(deps))
+	for name := range deps {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names, nil
+}
@@ -0,0 +1,282 @@
+package sema
+
+import (
+	"testing"
+
+	"github.com/isgasho/xsqlparser/sqlast"
+)
+
+func TestResolve_GroupByAlias(t *testing.T) {
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.ExpressionWithAlias{
+						Expr:  sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a")),
+						Alias: sqlast.NewSQLIdent("b"),
+					},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t")},
+				GroupBy:  []sqlast.ASTNode{sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("b"))},
+			},
+		},
+	}
+
+	resolved, err := Resolve(query, Schema{"t": {"a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if got := resolved.Query.Body.(*sqlast.SelectExpr).Select.GroupBy[0].Eval(); got != "a" {
+		t.Errorf("GROUP BY alias must resolve to %q but %q", "a", got)
+	}
+}
+
+func TestResolve_HavingAliasThroughBinaryExpr(t *testing.T) {
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.ExpressionWithAlias{
+						Expr: &sqlast.SQLBinaryExpr{
+							Left:  sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a")),
+							Op:    sqlast.Eq,
+							Right: sqlast.NewLongValue(1),
+						},
+						Alias: sqlast.NewSQLIdent("x"),
+					},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t")},
+				Having: &sqlast.SQLBinaryExpr{
+					Left:  sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("x")),
+					Op:    sqlast.Gt,
+					Right: sqlast.NewLongValue(0),
+				},
+			},
+		},
+	}
+
+	resolved, err := Resolve(query, Schema{"t": {"a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	having := resolved.Query.Body.(*sqlast.SelectExpr).Select.Having.(*sqlast.SQLBinaryExpr)
+	left, ok := having.Left.(*sqlast.SQLBinaryExpr)
+	if !ok {
+		t.Fatalf("HAVING's alias reference must be replaced by its aliased expression, got %#v", having.Left)
+	}
+	if got := left.Eval(); got != "a = 1" {
+		t.Errorf("must be %q but %q", "a = 1", got)
+	}
+}
+
+func TestResolve_AmbiguousAliasVsColumn(t *testing.T) {
+	// "a" is both a projected alias (for "b") and a genuine column of t; an
+	// unqualified GROUP BY reference to "a" must resolve to the real column,
+	// not the alias, so grouping by "a" does not make "b" a valid bare
+	// projection (it never appears in the, now-unresolved, GROUP BY list).
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.ExpressionWithAlias{
+						Expr:  sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("b")),
+						Alias: sqlast.NewSQLIdent("a"),
+					},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t")},
+				GroupBy:  []sqlast.ASTNode{sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a"))},
+			},
+		},
+	}
+
+	_, err := Resolve(query, Schema{"t": {"a", "b"}})
+	semaErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected a *sema.Error, got %v", err)
+	}
+	if semaErr.Code != ErrGroupOnNonGroupField {
+		t.Errorf("must be ErrGroupOnNonGroupField but %v", semaErr.Code)
+	}
+}
+
+func TestResolve_GroupByAliasStillAppliesWhenNotAColumn(t *testing.T) {
+	// "b" is only a projection alias (t has no such column), so the
+	// alias-vs-column precedence rule does not apply and GROUP BY resolution
+	// still substitutes it, as in TestResolve_GroupByAlias.
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.ExpressionWithAlias{
+						Expr:  sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a")),
+						Alias: sqlast.NewSQLIdent("b"),
+					},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t")},
+				GroupBy:  []sqlast.ASTNode{sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("b"))},
+			},
+		},
+	}
+
+	resolved, err := Resolve(query, Schema{"t": {"a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := resolved.Query.Body.(*sqlast.SelectExpr).Select.GroupBy[0].Eval(); got != "a" {
+		t.Errorf("GROUP BY alias must resolve to %q but %q", "a", got)
+	}
+}
+
+func TestResolve_UsingJoinColumnNotAmbiguous(t *testing.T) {
+	// Both t1 and t2 expose "id"; USING(id) merges it into a single shared
+	// column, so an unqualified reference must not be rejected as ambiguous.
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.UnnamedExpression{Node: sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("id"))},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t1")},
+				Joins: []*sqlast.Join{
+					{
+						Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t2")},
+						Op:       sqlast.Inner,
+						Constant: &sqlast.UsingConstant{Idents: []*sqlast.SQLIdent{sqlast.NewSQLIdent("id")}},
+					},
+				},
+			},
+		},
+	}
+
+	resolved, err := Resolve(query, Schema{"t1": {"id"}, "t2": {"id", "name"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	deps := resolved.Deps[resolved.Projection[0].(*sqlast.UnnamedExpression).Node]
+	if len(deps) != 2 || deps[0] != "t1" || deps[1] != "t2" {
+		t.Errorf("shared USING column must depend on both tables but %v", deps)
+	}
+}
+
+func TestResolve_DuplicateOutputName(t *testing.T) {
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.UnnamedExpression{Node: sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a"))},
+					&sqlast.ExpressionWithAlias{
+						Expr:  sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("b")),
+						Alias: sqlast.NewSQLIdent("a"),
+					},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t")},
+			},
+		},
+	}
+
+	_, err := Resolve(query, Schema{"t": {"a", "b"}})
+	semaErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected a *sema.Error, got %v", err)
+	}
+	if semaErr.Code != ErrDupFieldName {
+		t.Errorf("must be ErrDupFieldName but %v", semaErr.Code)
+	}
+	if semaErr.SQLState != "42S21" {
+		t.Errorf("must be SQLSTATE 42S21 but %s", semaErr.SQLState)
+	}
+}
+
+func TestResolve_WildcardExpansionAndDeps(t *testing.T) {
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.UnnamedExpression{Node: &sqlast.Wildcard{}},
+				},
+				Relation:  &sqlast.Table{Name: sqlast.NewSQLObjectName("t"), Alias: sqlast.NewSQLIdent("t")},
+				Selection: sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a")),
+			},
+		},
+	}
+
+	resolved, err := Resolve(query, Schema{"t": {"a", "b"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resolved.Projection) != 2 {
+		t.Fatalf("wildcard must expand to 2 columns but %d", len(resolved.Projection))
+	}
+	if got := resolved.Projection[0].Eval(); got != "t.a" {
+		t.Errorf("must be %q but %q", "t.a", got)
+	}
+
+	sel := resolved.Query.Body.(*sqlast.SelectExpr).Select
+	deps := resolved.Deps[sel.Selection]
+	if len(deps) != 1 || deps[0] != "t" {
+		t.Errorf("WHERE a must depend on table %q but %v", "t", deps)
+	}
+}
+
+func TestResolve_InSubQueryDeps(t *testing.T) {
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.UnnamedExpression{Node: &sqlast.Wildcard{}},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t"), Alias: sqlast.NewSQLIdent("t")},
+				Selection: &sqlast.SQLInSubQuery{
+					Expr: sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a")),
+					SubQuery: &sqlast.SQLQuery{
+						Body: &sqlast.SelectExpr{
+							Select: &sqlast.SQLSelect{
+								Projection: []sqlast.SQLSelectItem{
+									&sqlast.UnnamedExpression{Node: sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("a"))},
+								},
+								Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("u")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	resolved, err := Resolve(query, Schema{"t": {"a", "b"}, "u": {"a"}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	sel := resolved.Query.Body.(*sqlast.SelectExpr).Select
+	deps := resolved.Deps[sel.Selection]
+	if len(deps) != 1 || deps[0] != "t" {
+		t.Errorf("WHERE a IN (...) must depend on table %q but %v", "t", deps)
+	}
+}
+
+func TestResolve_UnknownColumn(t *testing.T) {
+	query := &sqlast.SQLQuery{
+		Body: &sqlast.SelectExpr{
+			Select: &sqlast.SQLSelect{
+				Projection: []sqlast.SQLSelectItem{
+					&sqlast.UnnamedExpression{Node: sqlast.NewSQLIdentifier(sqlast.NewSQLIdent("missing"))},
+				},
+				Relation: &sqlast.Table{Name: sqlast.NewSQLObjectName("t")},
+			},
+		},
+	}
+
+	_, err := Resolve(query, Schema{"t": {"a"}})
+	semaErr, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("expected a *sema.Error, got %v", err)
+	}
+	if semaErr.Code != ErrUnknownColumn {
+		t.Errorf("must be ErrUnknownColumn but %v", semaErr.Code)
+	}
+}
@@ -0,0 +1,454 @@
+package sqlast
+
+import (
+	"reflect"
+	"sort"
+)
+
+// Visitor visits AST nodes. Visit is called with the node being visited; if
+// it returns a non-nil Visitor w, Walk visits each of the children of node
+// with w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node ASTNode) (w Visitor, err error)
+}
+
+// Walk traverses an AST in depth-first order: it starts by calling
+// v.Visit(node); node must not be nil. If the visitor w returned by
+// v.Visit(node) is not nil, Walk visits each of the children of node with
+// the visitor w, followed by a call of w.Visit(nil).
+func Walk(v Visitor, node ASTNode) error {
+	if node == nil {
+		return nil
+	}
+
+	w, err := v.Visit(node)
+	if err != nil {
+		return err
+	}
+	if w == nil {
+		return nil
+	}
+
+	switch n := node.(type) {
+	case *SQLQuery:
+		for _, cte := range n.CTEs {
+			if err := Walk(w, cte.Query); err != nil {
+				return err
+			}
+		}
+		if err := Walk(w, n.Body); err != nil {
+			return err
+		}
+		for _, o := range n.OrderBy {
+			if err := Walk(w, o); err != nil {
+				return err
+			}
+		}
+		if err := Walk(w, n.Limit); err != nil {
+			return err
+		}
+		if err := Walk(w, n.Offset); err != nil {
+			return err
+		}
+		if n.Fetch != nil {
+			if err := Walk(w, n.Fetch); err != nil {
+				return err
+			}
+		}
+	case *SelectExpr:
+		if err := Walk(w, n.Select); err != nil {
+			return err
+		}
+	case *QueryExpr:
+		if err := Walk(w, n.Query); err != nil {
+			return err
+		}
+	case *SetOperationExpr:
+		if err := Walk(w, n.Left); err != nil {
+			return err
+		}
+		if err := Walk(w, n.Right); err != nil {
+			return err
+		}
+	case *SQLSelect:
+		for _, p := range n.Projection {
+			if err := Walk(w, p); err != nil {
+				return err
+			}
+		}
+		if rel, ok := n.Relation.(ASTNode); ok {
+			if err := Walk(w, rel); err != nil {
+				return err
+			}
+		}
+		for _, j := range n.Joins {
+			if err := Walk(w, j); err != nil {
+				return err
+			}
+		}
+		if err := Walk(w, n.Selection); err != nil {
+			return err
+		}
+		for _, g := range n.GroupBy {
+			if err := Walk(w, g); err != nil {
+				return err
+			}
+		}
+		if err := Walk(w, n.Having); err != nil {
+			return err
+		}
+		if len(n.Windows) != 0 {
+			names := make([]string, 0, len(n.Windows))
+			for name := range n.Windows {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			for _, name := range names {
+				if err := Walk(w, n.Windows[name]); err != nil {
+					return err
+				}
+			}
+		}
+		if err := Walk(w, n.Qualify); err != nil {
+			return err
+		}
+	case *Join:
+		if rel, ok := n.Relation.(ASTNode); ok {
+			if err := Walk(w, rel); err != nil {
+				return err
+			}
+		}
+		if oc, ok := n.Constant.(*OnJoinConstant); ok {
+			if err := Walk(w, oc.Node); err != nil {
+				return err
+			}
+		}
+	case *Table:
+		if err := Walk(w, n.Name); err != nil {
+			return err
+		}
+		for _, a := range n.Args {
+			if err := Walk(w, a); err != nil {
+				return err
+			}
+		}
+		for _, h := range n.WithHints {
+			if err := Walk(w, h); err != nil {
+				return err
+			}
+		}
+	case *Derived:
+		if err := Walk(w, n.SubQuery); err != nil {
+			return err
+		}
+	case *UnnamedExpression:
+		if err := Walk(w, n.Node); err != nil {
+			return err
+		}
+	case *ExpressionWithAlias:
+		if err := Walk(w, n.Expr); err != nil {
+			return err
+		}
+	case *QualifiedWildcard:
+		if err := Walk(w, n.Prefix); err != nil {
+			return err
+		}
+	case *SQLOrderByExpr:
+		if err := Walk(w, n.Expr); err != nil {
+			return err
+		}
+	default:
+		if err := walkGeneric(w, node); err != nil {
+			return err
+		}
+	}
+
+	_, err = w.Visit(nil)
+	return err
+}
+
+// walkGeneric visits node's children for node types that have no explicit
+// case above. Expression nodes (SQLBinaryExpr, SQLFunction, and the like)
+// outnumber the statement/clause nodes enumerated here, so rather than add
+// a case for every one of them, walkGeneric reflects over node's exported
+// fields and descends into any that are themselves an ASTNode or a slice of
+// ASTNode, which is how every expression node threads its operands.
+func walkGeneric(w Visitor, node ASTNode) error {
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return nil
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	astNodeType := reflect.TypeOf((*ASTNode)(nil)).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanInterface() {
+			continue
+		}
+
+		switch {
+		case field.Type().Implements(astNodeType):
+			if child, ok := field.Interface().(ASTNode); ok {
+				if err := Walk(w, child); err != nil {
+					return err
+				}
+			}
+		case field.Kind() == reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				elem := field.Index(j)
+				if !elem.CanInterface() || !elem.Type().Implements(astNodeType) {
+					continue
+				}
+				if child, ok := elem.Interface().(ASTNode); ok {
+					if err := Walk(w, child); err != nil {
+						return err
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: it starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f for
+// all the children of node, recursively.
+func Inspect(node ASTNode, f func(ASTNode) bool) error {
+	return Walk(inspector(f), node)
+}
+
+type inspector func(ASTNode) bool
+
+func (f inspector) Visit(node ASTNode) (Visitor, error) {
+	if node == nil {
+		return nil, nil
+	}
+	if f(node) {
+		return f, nil
+	}
+	return nil, nil
+}
+
+// Cursor describes an AST node encountered during Rewrite and allows the
+// node to be replaced in place.
+type Cursor struct {
+	node    ASTNode
+	parent  ASTNode
+	replace func(ASTNode)
+}
+
+// Node returns the current node.
+func (c Cursor) Node() ASTNode {
+	return c.node
+}
+
+// Parent returns the parent of the current node.
+func (c Cursor) Parent() ASTNode {
+	return c.parent
+}
+
+// Replace replaces the current node with n.
+func (c Cursor) Replace(n ASTNode) {
+	if c.replace != nil {
+		c.replace(n)
+	}
+}
+
+// Rewrite traverses an AST in depth-first order: it starts by calling
+// pre(cursor); if pre returns false, Rewrite returns without visiting the
+// children of the node. Otherwise Rewrite visits each child with the same
+// pre/post pair, then calls post(cursor). Either pre or post may be nil, in
+// which case it is treated as always returning true. Rewrite returns the
+// (possibly replaced) node.
+func Rewrite(node ASTNode, pre, post func(Cursor) bool) ASTNode {
+	parent := struct{ node ASTNode }{node: node}
+	rewrite(&parent.node, nil, pre, post)
+	return parent.node
+}
+
+func rewrite(nodePtr *ASTNode, parent ASTNode, pre, post func(Cursor) bool) {
+	node := *nodePtr
+	if node == nil {
+		return
+	}
+
+	cursor := Cursor{
+		node:   node,
+		parent: parent,
+		replace: func(n ASTNode) {
+			*nodePtr = n
+		},
+	}
+
+	if pre != nil && !pre(cursor) {
+		return
+	}
+
+	switch n := (*nodePtr).(type) {
+	case *SQLQuery:
+		for _, cte := range n.CTEs {
+			var q ASTNode = cte.Query
+			rewrite(&q, n, pre, post)
+			cte.Query, _ = q.(*SQLQuery)
+		}
+		var body ASTNode = n.Body
+		rewrite(&body, n, pre, post)
+		n.Body, _ = body.(SQLSetExpr)
+		for i := range n.OrderBy {
+			var o ASTNode = n.OrderBy[i]
+			rewrite(&o, n, pre, post)
+			n.OrderBy[i], _ = o.(*SQLOrderByExpr)
+		}
+		rewrite(&n.Limit, n, pre, post)
+		rewrite(&n.Offset, n, pre, post)
+		if n.Fetch != nil {
+			var f ASTNode = n.Fetch
+			rewrite(&f, n, pre, post)
+			n.Fetch, _ = f.(*FetchExpr)
+		}
+	case *SelectExpr:
+		var sel ASTNode = n.Select
+		rewrite(&sel, n, pre, post)
+		n.Select, _ = sel.(*SQLSelect)
+	case *QueryExpr:
+		var q ASTNode = n.Query
+		rewrite(&q, n, pre, post)
+		n.Query, _ = q.(*SQLQuery)
+	case *SetOperationExpr:
+		var left ASTNode = n.Left
+		rewrite(&left, n, pre, post)
+		n.Left, _ = left.(SQLSetExpr)
+		var right ASTNode = n.Right
+		rewrite(&right, n, pre, post)
+		n.Right, _ = right.(SQLSetExpr)
+	case *SQLSelect:
+		for i := range n.Projection {
+			var item ASTNode = n.Projection[i]
+			rewrite(&item, n, pre, post)
+			n.Projection[i], _ = item.(SQLSelectItem)
+		}
+		if rel, ok := n.Relation.(ASTNode); ok {
+			rewrite(&rel, n, pre, post)
+			if tf, ok := rel.(TableFactor); ok {
+				n.Relation = tf
+			}
+		}
+		for i := range n.Joins {
+			var j ASTNode = n.Joins[i]
+			rewrite(&j, n, pre, post)
+			n.Joins[i], _ = j.(*Join)
+		}
+		rewrite(&n.Selection, n, pre, post)
+		for i := range n.GroupBy {
+			rewrite(&n.GroupBy[i], n, pre, post)
+		}
+		rewrite(&n.Having, n, pre, post)
+		for name, win := range n.Windows {
+			var w ASTNode = win
+			rewrite(&w, n, pre, post)
+			n.Windows[name], _ = w.(*Window)
+		}
+		rewrite(&n.Qualify, n, pre, post)
+	case *Join:
+		if rel, ok := n.Relation.(ASTNode); ok {
+			rewrite(&rel, n, pre, post)
+			if tf, ok := rel.(TableFactor); ok {
+				n.Relation = tf
+			}
+		}
+		if oc, ok := n.Constant.(*OnJoinConstant); ok {
+			rewrite(&oc.Node, n, pre, post)
+		}
+	case *UnnamedExpression:
+		rewrite(&n.Node, n, pre, post)
+	case *ExpressionWithAlias:
+		rewrite(&n.Expr, n, pre, post)
+	case *Derived:
+		var q ASTNode = n.SubQuery
+		rewrite(&q, n, pre, post)
+		n.SubQuery, _ = q.(*SQLQuery)
+	case *SQLOrderByExpr:
+		rewrite(&n.Expr, n, pre, post)
+	case *Table:
+		var name ASTNode = n.Name
+		rewrite(&name, n, pre, post)
+		n.Name, _ = name.(*SQLObjectName)
+		for i := range n.Args {
+			rewrite(&n.Args[i], n, pre, post)
+		}
+		for i := range n.WithHints {
+			rewrite(&n.WithHints[i], n, pre, post)
+		}
+	case *QualifiedWildcard:
+		var prefix ASTNode = n.Prefix
+		rewrite(&prefix, n, pre, post)
+		n.Prefix, _ = prefix.(*SQLObjectName)
+	default:
+		rewriteGeneric(nodePtr, pre, post)
+	}
+
+	if post != nil {
+		post(Cursor{node: *nodePtr, parent: parent, replace: cursor.replace})
+	}
+}
+
+// rewriteGeneric rewrites node's children for node types that have no
+// explicit case above, mirroring walkGeneric: it reflects over the node's
+// exported fields and descends into any that are themselves an ASTNode or a
+// slice of ASTNode, writing any replacement back into the field.
+func rewriteGeneric(nodePtr *ASTNode, pre, post func(Cursor) bool) {
+	node := *nodePtr
+	v := reflect.ValueOf(node)
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return
+	}
+	v = v.Elem()
+	if v.Kind() != reflect.Struct {
+		return
+	}
+
+	astNodeType := reflect.TypeOf((*ASTNode)(nil)).Elem()
+
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Field(i)
+		if !field.CanInterface() || !field.CanSet() {
+			continue
+		}
+
+		switch {
+		case field.Type().Implements(astNodeType):
+			child, _ := field.Interface().(ASTNode)
+			if child == nil {
+				continue
+			}
+			rewrite(&child, node, pre, post)
+			if child == nil {
+				field.Set(reflect.Zero(field.Type()))
+			} else if reflect.TypeOf(child).AssignableTo(field.Type()) {
+				field.Set(reflect.ValueOf(child))
+			}
+		case field.Kind() == reflect.Slice:
+			for j := 0; j < field.Len(); j++ {
+				elem := field.Index(j)
+				if !elem.CanInterface() || !elem.Type().Implements(astNodeType) {
+					continue
+				}
+				child, _ := elem.Interface().(ASTNode)
+				if child == nil {
+					continue
+				}
+				rewrite(&child, node, pre, post)
+				if child == nil {
+					elem.Set(reflect.Zero(elem.Type()))
+				} else if reflect.TypeOf(child).AssignableTo(elem.Type()) {
+					elem.Set(reflect.ValueOf(child))
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,303 @@
+package sqlast
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestInspect_CollectSQLObjectNames(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SQLSelect{
+			Projection: []SQLSelectItem{
+				&UnnamedExpression{Node: NewSQLIdentifier(NewSQLIdent("id"))},
+			},
+			Relation: &Table{
+				Name: NewSQLObjectName("customers"),
+			},
+			Joins: []*Join{
+				{
+					Relation: &Table{
+						Name: NewSQLObjectName("orders"),
+					},
+					Op: Inner,
+					Constant: &OnJoinConstant{
+						Node: &SQLBinaryExpr{
+							Left: &SQLCompoundIdentifier{
+								Idents: []*SQLIdent{NewSQLIdent("customers"), NewSQLIdent("id")},
+							},
+							Op: Eq,
+							Right: &SQLCompoundIdentifier{
+								Idents: []*SQLIdent{NewSQLIdent("orders"), NewSQLIdent("customer_id")},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	var names []string
+	err := Inspect(query, func(node ASTNode) bool {
+		if on, ok := node.(*SQLObjectName); ok {
+			names = append(names, on.Eval())
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"customers", "orders"}
+	if !reflect.DeepEqual(names, expected) {
+		t.Errorf("must be %v but %v", expected, names)
+	}
+}
+
+func TestRewrite_WildcardToColumnList(t *testing.T) {
+	sel := &SQLSelect{
+		Projection: []SQLSelectItem{
+			&UnnamedExpression{Node: &SQLWildcard{}},
+		},
+		Relation: &Table{
+			Name: NewSQLObjectName("t"),
+		},
+	}
+
+	rewritten := Rewrite(sel, nil, func(c Cursor) bool {
+		if _, ok := c.Node().(*SQLWildcard); ok {
+			c.Replace(NewSQLIdentifier(NewSQLIdent("id")))
+		}
+		return true
+	})
+
+	out := rewritten.(*SQLSelect).Eval()
+	expected := "SELECT id FROM t"
+	if out != expected {
+		t.Errorf("must be %s but %s", expected, out)
+	}
+}
+
+func TestRewrite_WildcardInJoinedDerived(t *testing.T) {
+	sel := &SQLSelect{
+		Projection: []SQLSelectItem{
+			&UnnamedExpression{Node: &SQLWildcard{}},
+		},
+		Relation: &Table{
+			Name: NewSQLObjectName("t1"),
+		},
+		Joins: []*Join{
+			{
+				Relation: &Derived{
+					SubQuery: &SQLQuery{
+						Body: &SQLSelect{
+							Projection: []SQLSelectItem{
+								&UnnamedExpression{Node: &SQLWildcard{}},
+							},
+							Relation: &Table{
+								Name: NewSQLObjectName("t2"),
+							},
+						},
+					},
+					Alias: NewSQLIdent("sub"),
+				},
+				Op: Cross,
+			},
+		},
+	}
+
+	var hits int
+	Rewrite(sel, nil, func(c Cursor) bool {
+		if _, ok := c.Node().(*SQLWildcard); ok {
+			hits++
+			c.Replace(NewSQLIdentifier(NewSQLIdent("id")))
+		}
+		return true
+	})
+
+	if hits != 2 {
+		t.Errorf("expected to rewrite 2 wildcards (outer select and derived subquery) but got %d", hits)
+	}
+}
+
+func TestRewrite_WildcardInsideExpression(t *testing.T) {
+	sel := &SQLSelect{
+		Projection: []SQLSelectItem{
+			&UnnamedExpression{Node: &SQLWildcard{}},
+		},
+		Relation: &Table{
+			Name: NewSQLObjectName("t"),
+		},
+		Selection: &SQLBinaryExpr{
+			Left:  &SQLWildcard{},
+			Op:    Eq,
+			Right: NewSQLIdentifier(NewSQLIdent("id")),
+		},
+	}
+
+	var hits int
+	Rewrite(sel, nil, func(c Cursor) bool {
+		if _, ok := c.Node().(*SQLWildcard); ok {
+			hits++
+			c.Replace(NewSQLIdentifier(NewSQLIdent("id")))
+		}
+		return true
+	})
+
+	if hits != 2 {
+		t.Errorf("expected to rewrite 2 wildcards (projection and WHERE expression) but got %d", hits)
+	}
+}
+
+func TestInspect_CollectsWindowsQualifyOffsetFetch(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("t"),
+				},
+				Windows: map[string]*Window{
+					"w1": {
+						OrderBy: []*SQLOrderByExpr{
+							{Expr: NewSQLIdentifier(NewSQLIdent("win_col"))},
+						},
+					},
+				},
+				Qualify: NewSQLIdentifier(NewSQLIdent("qualify_col")),
+			},
+		},
+		OrderBy: []*SQLOrderByExpr{
+			{Expr: NewSQLIdentifier(NewSQLIdent("order_col"))},
+		},
+		Offset: NewSQLIdentifier(NewSQLIdent("offset_col")),
+		Fetch: &FetchExpr{
+			Count: NewSQLIdentifier(NewSQLIdent("fetch_col")),
+		},
+	}
+
+	var names []string
+	err := Inspect(query, func(node ASTNode) bool {
+		if id, ok := node.(*SQLIdentifier); ok {
+			names = append(names, id.Eval())
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	expected := []string{"win_col", "qualify_col", "order_col", "offset_col", "fetch_col"}
+	for _, name := range expected {
+		var found bool
+		for _, got := range names {
+			if got == name {
+				found = true
+				break
+			}
+		}
+		if !found {
+			t.Errorf("expected Inspect to visit %q, got %v", name, names)
+		}
+	}
+}
+
+func TestRewrite_WindowsQualifyOffsetFetch(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("t"),
+				},
+				Windows: map[string]*Window{
+					"w1": {
+						OrderBy: []*SQLOrderByExpr{
+							{Expr: &SQLWildcard{}},
+						},
+					},
+				},
+				Qualify: &SQLWildcard{},
+			},
+		},
+		OrderBy: []*SQLOrderByExpr{
+			{Expr: &SQLWildcard{}},
+		},
+		Offset: &SQLWildcard{},
+		Fetch: &FetchExpr{
+			Count: &SQLWildcard{},
+		},
+	}
+
+	var hits int
+	Rewrite(query, nil, func(c Cursor) bool {
+		if _, ok := c.Node().(*SQLWildcard); ok {
+			hits++
+			c.Replace(NewSQLIdentifier(NewSQLIdent("id")))
+		}
+		return true
+	})
+
+	if hits != 6 {
+		t.Errorf("expected to rewrite 6 wildcards (projection, window order-by, qualify, query order-by, offset, fetch count) but got %d", hits)
+	}
+}
+
+func TestInspect_CorrelatedReferenceInDerived(t *testing.T) {
+	query := &SQLSelect{
+		Projection: []SQLSelectItem{
+			&UnnamedExpression{Node: &SQLWildcard{}},
+		},
+		Relation: &Table{
+			Name:  NewSQLObjectName("t1"),
+			Alias: NewSQLIdent("t1"),
+		},
+		Joins: []*Join{
+			{
+				Relation: &Derived{
+					SubQuery: &SQLQuery{
+						Body: &SQLSelect{
+							Projection: []SQLSelectItem{
+								&UnnamedExpression{Node: &SQLWildcard{}},
+							},
+							Relation: &Table{
+								Name: NewSQLObjectName("t2"),
+							},
+							Selection: &SQLBinaryExpr{
+								Left: &SQLCompoundIdentifier{
+									Idents: []*SQLIdent{NewSQLIdent("t2"), NewSQLIdent("x")},
+								},
+								Op: Eq,
+								Right: &SQLCompoundIdentifier{
+									Idents: []*SQLIdent{NewSQLIdent("t1"), NewSQLIdent("x")},
+								},
+							},
+						},
+					},
+					Alias: NewSQLIdent("sub"),
+				},
+				Op: Cross,
+			},
+		},
+	}
+
+	var correlated bool
+	err := Inspect(query, func(node ASTNode) bool {
+		if ci, ok := node.(*SQLCompoundIdentifier); ok {
+			if len(ci.Idents) > 0 && string(*ci.Idents[0]) == "t1" {
+				correlated = true
+			}
+		}
+		return true
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+
+	if !correlated {
+		t.Error("expected to find a correlated reference to t1 inside the derived subquery")
+	}
+}
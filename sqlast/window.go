@@ -0,0 +1,217 @@
+package sqlast
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Window describes the OVER clause of a window function:
+// OVER (PARTITION BY ... ORDER BY ... <frame clause>).
+//
+// A Window is rendered through SQLSelect.Windows as a named definition in
+// the WINDOW clause, and Walk/Rewrite visit it there. SQLFunction has no
+// field holding a Window, so a function call such as ROW_NUMBER() cannot
+// carry an inline OVER (...) clause.
+type Window struct {
+	posRange
+	PartitionBy []ASTNode
+	OrderBy     []*SQLOrderByExpr
+	Frame       *WindowFrame
+}
+
+func (w *Window) Eval() string {
+	var buf bytes.Buffer
+	_ = w.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (w *Window) Format(out io.Writer, dialect Dialect) error {
+	var parts []string
+
+	if len(w.PartitionBy) != 0 {
+		partitionStr, err := formatCommaSeparated(dialect, w.PartitionBy)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", dialect.Keyword("PARTITION BY"), partitionStr))
+	}
+
+	if len(w.OrderBy) != 0 {
+		orderNodes := make([]ASTNode, len(w.OrderBy))
+		for i, o := range w.OrderBy {
+			orderNodes[i] = o
+		}
+		orderStr, err := formatCommaSeparated(dialect, orderNodes)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, fmt.Sprintf("%s %s", dialect.Keyword("ORDER BY"), orderStr))
+	}
+
+	if w.Frame != nil {
+		frameStr, err := formatEval(dialect, w.Frame)
+		if err != nil {
+			return err
+		}
+		parts = append(parts, frameStr)
+	}
+
+	_, err := io.WriteString(out, strings.Join(parts, " "))
+	return err
+}
+
+// WindowFrameUnit is the unit of a window frame clause: ROWS or RANGE.
+type WindowFrameUnit int
+
+const (
+	RowsFrameUnit WindowFrameUnit = iota
+	RangeFrameUnit
+)
+
+func (u WindowFrameUnit) Eval() string {
+	var buf bytes.Buffer
+	_ = u.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (u WindowFrameUnit) Format(out io.Writer, dialect Dialect) error {
+	if u == RangeFrameUnit {
+		_, err := io.WriteString(out, dialect.Keyword("RANGE"))
+		return err
+	}
+	_, err := io.WriteString(out, dialect.Keyword("ROWS"))
+	return err
+}
+
+// WindowFrameBoundType is the kind of boundary in a window frame clause.
+type WindowFrameBoundType int
+
+const (
+	FrameUnboundedPreceding WindowFrameBoundType = iota
+	FramePreceding
+	FrameCurrentRow
+	FrameFollowing
+	FrameUnboundedFollowing
+)
+
+// WindowFrameBound is one endpoint of a ROWS/RANGE BETWEEN clause.
+// Offset is set (and rendered) only for FramePreceding and FrameFollowing.
+type WindowFrameBound struct {
+	posRange
+	Type   WindowFrameBoundType
+	Offset ASTNode
+}
+
+func (b *WindowFrameBound) Eval() string {
+	var buf bytes.Buffer
+	_ = b.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (b *WindowFrameBound) Format(out io.Writer, dialect Dialect) error {
+	var s string
+	switch b.Type {
+	case FrameUnboundedPreceding:
+		s = dialect.Keyword("UNBOUNDED PRECEDING")
+	case FramePreceding:
+		offsetStr, err := formatEval(dialect, b.Offset)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf("%s %s", offsetStr, dialect.Keyword("PRECEDING"))
+	case FrameCurrentRow:
+		s = dialect.Keyword("CURRENT ROW")
+	case FrameFollowing:
+		offsetStr, err := formatEval(dialect, b.Offset)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf("%s %s", offsetStr, dialect.Keyword("FOLLOWING"))
+	case FrameUnboundedFollowing:
+		s = dialect.Keyword("UNBOUNDED FOLLOWING")
+	}
+	_, err := io.WriteString(out, s)
+	return err
+}
+
+// WindowFrame is the frame clause of a Window, e.g.
+// "ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW". EndBound may be nil,
+// in which case only the StartBound is rendered (no BETWEEN ... AND).
+type WindowFrame struct {
+	posRange
+	Unit       WindowFrameUnit
+	StartBound *WindowFrameBound
+	EndBound   *WindowFrameBound
+}
+
+func (f *WindowFrame) Eval() string {
+	var buf bytes.Buffer
+	_ = f.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (f *WindowFrame) Format(out io.Writer, dialect Dialect) error {
+	unitStr, err := formatEval(dialect, f.Unit)
+	if err != nil {
+		return err
+	}
+	startStr, err := formatEval(dialect, f.StartBound)
+	if err != nil {
+		return err
+	}
+
+	var s string
+	if f.EndBound != nil {
+		endStr, err := formatEval(dialect, f.EndBound)
+		if err != nil {
+			return err
+		}
+		s = fmt.Sprintf("%s %s %s %s %s", unitStr, dialect.Keyword("BETWEEN"), startStr, dialect.Keyword("AND"), endStr)
+	} else {
+		s = fmt.Sprintf("%s %s", unitStr, startStr)
+	}
+
+	_, err = io.WriteString(out, s)
+	return err
+}
+
+// FetchExpr renders an ANSI FETCH FIRST/NEXT clause
+// ("FETCH FIRST n ROWS ONLY" or "... ROWS WITH TIES"), used as an
+// alternative to SQLQuery.Limit.
+type FetchExpr struct {
+	posRange
+	Count    ASTNode
+	WithTies bool
+}
+
+func (f *FetchExpr) Eval() string {
+	var buf bytes.Buffer
+	_ = f.Format(&buf, AnsiDialect)
+	return buf.String()
+}
+
+func (f *FetchExpr) Format(out io.Writer, dialect Dialect) error {
+	count := "1"
+	if f.Count != nil {
+		countStr, err := formatEval(dialect, f.Count)
+		if err != nil {
+			return err
+		}
+		count = countStr
+	}
+
+	rowsWord := dialect.Keyword("ROWS")
+	if count == "1" {
+		rowsWord = dialect.Keyword("ROW")
+	}
+
+	tieWord := dialect.Keyword("ONLY")
+	if f.WithTies {
+		tieWord = dialect.Keyword("WITH TIES")
+	}
+
+	_, err := fmt.Fprintf(out, "%s %s %s %s", dialect.Keyword("FETCH FIRST"), count, rowsWord, tieWord)
+	return err
+}
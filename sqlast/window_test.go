@@ -0,0 +1,126 @@
+package sqlast
+
+import "testing"
+
+func TestWindow_Eval(t *testing.T) {
+	cases := []struct {
+		name string
+		in   *Window
+		out  string
+	}{
+		{
+			name: "partition and order by",
+			in: &Window{
+				PartitionBy: []ASTNode{NewSQLIdentifier(NewSQLIdent("dept"))},
+				OrderBy: []*SQLOrderByExpr{
+					{Expr: NewSQLIdentifier(NewSQLIdent("salary"))},
+				},
+			},
+			out: "PARTITION BY dept ORDER BY salary",
+		},
+		{
+			name: "with frame",
+			in: &Window{
+				OrderBy: []*SQLOrderByExpr{
+					{Expr: NewSQLIdentifier(NewSQLIdent("ts"))},
+				},
+				Frame: &WindowFrame{
+					Unit:       RowsFrameUnit,
+					StartBound: &WindowFrameBound{Type: FrameUnboundedPreceding},
+					EndBound:   &WindowFrameBound{Type: FrameCurrentRow},
+				},
+			},
+			out: "ORDER BY ts ROWS BETWEEN UNBOUNDED PRECEDING AND CURRENT ROW",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if act := c.in.Eval(); act != c.out {
+				t.Errorf("must be \n%s but \n%s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestFetchExpr_Eval(t *testing.T) {
+	cases := []struct {
+		name string
+		in   *FetchExpr
+		out  string
+	}{
+		{
+			name: "default count",
+			in:   &FetchExpr{},
+			out:  "FETCH FIRST 1 ROW ONLY",
+		},
+		{
+			name: "explicit count",
+			in:   &FetchExpr{Count: NewLongValue(5)},
+			out:  "FETCH FIRST 5 ROWS ONLY",
+		},
+		{
+			name: "with ties",
+			in:   &FetchExpr{Count: NewLongValue(5), WithTies: true},
+			out:  "FETCH FIRST 5 ROWS WITH TIES",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if act := c.in.Eval(); act != c.out {
+				t.Errorf("must be %s but %s", c.out, act)
+			}
+		})
+	}
+}
+
+func TestSQLQuery_Eval_OffsetAndFetch(t *testing.T) {
+	query := &SQLQuery{
+		Body: &SelectExpr{
+			Select: &SQLSelect{
+				Projection: []SQLSelectItem{
+					&UnnamedExpression{Node: &SQLWildcard{}},
+				},
+				Relation: &Table{
+					Name: NewSQLObjectName("test_table"),
+				},
+			},
+		},
+		Offset: NewLongValue(10),
+		Fetch:  &FetchExpr{Count: NewLongValue(5)},
+	}
+
+	expected := "SELECT * FROM test_table OFFSET 10 FETCH FIRST 5 ROWS ONLY"
+	if act := query.Eval(); act != expected {
+		t.Errorf("must be \n%s but \n%s", expected, act)
+	}
+}
+
+func TestSQLSelect_Eval_WindowAndQualify(t *testing.T) {
+	sel := &SQLSelect{
+		Projection: []SQLSelectItem{
+			&UnnamedExpression{Node: &SQLWildcard{}},
+		},
+		Relation: &Table{
+			Name: NewSQLObjectName("test_table"),
+		},
+		Windows: map[string]*Window{
+			"w1": {
+				OrderBy: []*SQLOrderByExpr{
+					{Expr: NewSQLIdentifier(NewSQLIdent("ts"))},
+				},
+			},
+		},
+		Qualify: &SQLBinaryExpr{
+			Left:  NewSQLIdentifier(NewSQLIdent("rn")),
+			Op:    Eq,
+			Right: NewLongValue(1),
+		},
+	}
+
+	expected := "SELECT * FROM test_table WINDOW w1 AS (ORDER BY ts) QUALIFY rn = 1"
+	if act := sel.Eval(); act != expected {
+		t.Errorf("must be \n%s but \n%s", expected, act)
+	}
+}